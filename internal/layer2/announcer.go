@@ -3,7 +3,6 @@ package layer2
 import (
 	"net"
 	"sync"
-	"time"
 
 	"github.com/go-kit/kit/log"
 )
@@ -12,115 +11,289 @@ import (
 type Announce struct {
 	logger         log.Logger
 	bindInterfaces []string
+	linkDetector   linkDetector
+	retries        *retryQueue
+	responders     *responderFactory
 
 	sync.RWMutex
-	arps   map[int]*arpResponder
-	ndps   map[int]*ndpResponder
+	arps   map[int]arpResponder
+	ndps   map[int]ndpResponder
 	ips    map[string]net.IP // map containing IPs we should announce
 	leader bool
+
+	// arpBindErrors and ndpBindErrors hold the last error seen creating a
+	// responder for an interface index, tracked separately per protocol:
+	// ARP and NDP can fail or succeed independently on the same interface
+	// (e.g. NDP needs a link-local address that ARP doesn't), so a single
+	// shared map would have one protocol's success wipe out the other's
+	// still-live error.
+	arpBindErrors map[int]error
+	ndpBindErrors map[int]error
+}
+
+// Option customizes the Announce returned by New.
+type Option func(*Announce)
+
+// withLinkDetector overrides the linkDetector used to learn about interface
+// changes, so a caller can inject synthetic events instead of talking to
+// netlink.
+func withLinkDetector(d linkDetector) Option {
+	return func(a *Announce) {
+		a.linkDetector = d
+	}
 }
 
 // New returns an initialized Announce.
-func New(l log.Logger, ifaces ...string) (*Announce, error) {
+func New(l log.Logger, ifaces []string, opts ...Option) (*Announce, error) {
 	ret := &Announce{
 		logger:         l,
 		bindInterfaces: ifaces,
-		arps:           map[int]*arpResponder{},
-		ndps:           map[int]*ndpResponder{},
+		arps:           map[int]arpResponder{},
+		ndps:           map[int]ndpResponder{},
 		ips:            make(map[string]net.IP),
+		arpBindErrors:  map[int]error{},
+		ndpBindErrors:  map[int]error{},
+	}
+	for _, opt := range opts {
+		opt(ret)
 	}
+
+	if ret.linkDetector == nil {
+		d, err := newLinkDetector(l)
+		if err != nil {
+			return nil, err
+		}
+		ret.linkDetector = d
+	}
+	ret.retries = newRetryQueue(l, ret.updateInterface)
+	ret.responders = newResponderFactory(l, ret.shouldAnnounce)
+
+	// Seed state once at startup, then react to link events from here on.
+	ret.updateInterfaces()
 	go ret.interfaceScan()
 
 	return ret, nil
 }
 
+// Close stops watching for interface changes and tears down every
+// responder, including those still waiting on a retry.
+func (a *Announce) Close() error {
+	a.retries.close()
+	a.linkDetector.Close()
+
+	a.Lock()
+	defer a.Unlock()
+	for i, client := range a.arps {
+		client.Close()
+		delete(a.arps, i)
+	}
+	for i, client := range a.ndps {
+		client.Close()
+		delete(a.ndps, i)
+	}
+	return nil
+}
+
 func (a *Announce) interfaceScan() {
-	for {
-		a.updateInterfaces()
-		time.Sleep(10 * time.Second)
+	for evt := range a.linkDetector.Events() {
+		if evt.Type == linkEventRescan {
+			a.updateInterfaces()
+			continue
+		}
+		a.updateInterface(evt.Index)
 	}
 }
 
+// updateInterfaces reconciles every interface currently on the host. It's
+// used to seed state at startup, and as a fallback on platforms where the
+// linkDetector can't tell us which interface changed.
 func (a *Announce) updateInterfaces() {
-	ifs, err := net.Interfaces()
+	ifs, err := interfaces()
 	if err != nil {
 		a.logger.Log("op", "getInterfaces", "error", err, "msg", "couldn't list interfaces")
 		return
 	}
 
-	a.Lock()
-	defer a.Unlock()
-
-	keepARP, keepNDP := map[int]bool{}, map[int]bool{}
+	seen := map[int]bool{}
 	for _, intf := range ifs {
 		ifi := intf
-		l := log.With(a.logger, "interface", ifi.Name)
-		addrs, err := ifi.Addrs()
-		if err != nil {
-			l.Log("op", "getAddresses", "error", err, "msg", "couldn't get addresses for interface")
-			return
-		}
+		seen[ifi.Index] = true
+		a.reconcileInterface(&ifi)
+	}
 
-		if ifi.Flags&net.FlagUp == 0 {
-			continue
+	a.Lock()
+	defer a.Unlock()
+	for i, client := range a.arps {
+		if !seen[i] {
+			client.Close()
+			delete(a.arps, i)
+			a.logger.Log("interface", client.Interface().Name, "event", "deleteARPResponder", "msg", "deleted ARP responder for interface")
+		}
+	}
+	for i, client := range a.ndps {
+		if !seen[i] {
+			client.Close()
+			delete(a.ndps, i)
+			a.logger.Log("interface", client.Interface().Name, "event", "deleteNDPResponder", "msg", "deleted NDP responder for interface")
 		}
+	}
+}
+
+// updateInterface reconciles responder state for a single interface index,
+// in response to a link or address event from the linkDetector.
+func (a *Announce) updateInterface(index int) {
+	ifi, err := interfaceByIndex(index)
+	if err != nil {
+		// The interface is gone, tear down anything we had for it.
+		a.Lock()
+		defer a.Unlock()
+		a.removeResponders(index)
+		return
+	}
+	a.reconcileInterface(ifi)
+}
+
+// reconcileInterface brings the ARP/NDP responders for a single interface in
+// line with its current flags and addresses.
+func (a *Announce) reconcileInterface(ifi *net.Interface) {
+	l := log.With(a.logger, "interface", ifi.Name)
+	addrs, err := interfaceAddrs(ifi)
+	if err != nil {
+		l.Log("op", "getAddresses", "error", err, "msg", "couldn't get addresses for interface")
+		return
+	}
 
-		if len(a.bindInterfaces) > 0 && !isBindInterface(ifi.Name, a.bindInterfaces) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.invalidateStaleResponders(ifi)
+
+	if ifi.Flags&net.FlagUp == 0 {
+		a.removeResponders(ifi.Index)
+		return
+	}
+
+	if len(a.bindInterfaces) > 0 && !isBindInterface(ifi.Name, a.bindInterfaces) {
+		a.removeResponders(ifi.Index)
+		return
+	}
+
+	keepARP := ifi.Flags&net.FlagBroadcast != 0
+	keepNDP := false
+	for _, addr := range addrs {
+		ipaddr, ok := addr.(*net.IPNet)
+		if !ok {
 			continue
 		}
-
-		if ifi.Flags&net.FlagBroadcast != 0 {
-			keepARP[ifi.Index] = true
+		if ipaddr.IP.To4() != nil || !ipaddr.IP.IsLinkLocalUnicast() {
+			continue
 		}
+		keepNDP = true
+		break
+	}
 
-		for _, a := range addrs {
-			ipaddr, ok := a.(*net.IPNet)
-			if !ok {
-				continue
-			}
-			if ipaddr.IP.To4() != nil || !ipaddr.IP.IsLinkLocalUnicast() {
-				continue
+	if keepARP {
+		if a.arps[ifi.Index] == nil {
+			resp, err := a.responders.newARP(ifi.Index)
+			if err != nil {
+				l.Log("op", "createARPResponder", "error", err, "msg", "failed to create ARP responder, will retry")
+				a.arpBindErrors[ifi.Index] = err
+				a.retries.scheduleRetry(ifi.Index)
+			} else {
+				a.arps[ifi.Index] = resp
+				delete(a.arpBindErrors, ifi.Index)
+				l.Log("event", "createARPResponder", "msg", "created ARP responder for interface")
 			}
-			keepNDP[ifi.Index] = true
-			break
 		}
+	} else {
+		delete(a.arpBindErrors, ifi.Index)
+		if client, ok := a.arps[ifi.Index]; ok {
+			client.Close()
+			delete(a.arps, ifi.Index)
+			l.Log("event", "deleteARPResponder", "msg", "deleted ARP responder for interface")
+		}
+	}
 
-		if keepARP[ifi.Index] && a.arps[ifi.Index] == nil {
-			resp, err := newARPResponder(a.logger, &ifi, a.shouldAnnounce)
+	if keepNDP {
+		if a.ndps[ifi.Index] == nil {
+			resp, err := a.responders.newNDP(ifi.Index)
 			if err != nil {
-				l.Log("op", "createARPResponder", "error", err, "msg", "failed to create ARP responder")
-				return
+				l.Log("op", "createNDPResponder", "error", err, "msg", "failed to create NDP responder, will retry")
+				a.ndpBindErrors[ifi.Index] = err
+				a.retries.scheduleRetry(ifi.Index)
+			} else {
+				a.ndps[ifi.Index] = resp
+				delete(a.ndpBindErrors, ifi.Index)
+				l.Log("event", "createNDPResponder", "msg", "created NDP responder for interface")
 			}
-			a.arps[ifi.Index] = resp
-			l.Log("event", "createARPResponder", "msg", "created ARP responder for interface")
 		}
-		if keepNDP[ifi.Index] && a.ndps[ifi.Index] == nil {
-			resp, err := newNDPResponder(a.logger, &ifi, a.shouldAnnounce)
-			if err != nil {
-				l.Log("op", "createNDPResponder", "error", err, "msg", "failed to create NDP responder")
-				return
-			}
-			a.ndps[ifi.Index] = resp
-			l.Log("event", "createNDPResponder", "msg", "created NDP responder for interface")
+	} else {
+		delete(a.ndpBindErrors, ifi.Index)
+		if client, ok := a.ndps[ifi.Index]; ok {
+			client.Close()
+			delete(a.ndps, ifi.Index)
+			l.Log("event", "deleteNDPResponder", "msg", "deleted NDP responder for interface")
 		}
 	}
 
-	for i, client := range a.arps {
-		if !keepARP[i] {
-			client.Close()
-			delete(a.arps, i)
-			a.logger.Log("interface", client.Interface(), "event", "deleteARPResponder", "msg", "deleted ARP responder for interface")
-		}
+	// Once every protocol this interface needs has a responder, reset its
+	// backoff so a fresh failure down the line (e.g. after the interface is
+	// recreated) starts retrying at retryInitialBackoff instead of inheriting
+	// whatever backoff accumulated before. Both protocols' errors have to be
+	// clear: if one still has an error outstanding, its retry is still
+	// needed and must not be cancelled just because the other succeeded.
+	if a.arpBindErrors[ifi.Index] == nil && a.ndpBindErrors[ifi.Index] == nil {
+		a.retries.cancel(ifi.Index)
 	}
-	for i, client := range a.ndps {
-		if !keepNDP[i] {
-			client.Close()
-			delete(a.ndps, i)
-			a.logger.Log("interface", client.Interface(), "event", "deleteNDPResponder", "msg", "deleted NDP responder for interface")
-		}
+}
+
+// invalidateStaleResponders tears down any responder at ifi.Index whose name
+// or MAC no longer matches ifi, i.e. the ifindex has been recycled for a
+// different logical interface since we last built a responder for it.
+// Callers must hold a.Lock().
+func (a *Announce) invalidateStaleResponders(ifi *net.Interface) {
+	stale := false
+	if client, ok := a.arps[ifi.Index]; ok && !sameInterface(client.Interface(), ifi) {
+		client.Close()
+		delete(a.arps, ifi.Index)
+		stale = true
+		a.logger.Log("interface", ifi.Name, "event", "deleteARPResponder", "msg", "interface identity changed, rebuilding ARP responder")
+	}
+	if client, ok := a.ndps[ifi.Index]; ok && !sameInterface(client.Interface(), ifi) {
+		client.Close()
+		delete(a.ndps, ifi.Index)
+		stale = true
+		a.logger.Log("interface", ifi.Name, "event", "deleteNDPResponder", "msg", "interface identity changed, rebuilding NDP responder")
 	}
 
-	return
+	// The old interface's backoff and bind errors have nothing to do with
+	// the new logical interface now sitting at this index: reset all of it,
+	// so a recycled ifindex starts retrying at retryInitialBackoff rather
+	// than inheriting whatever accumulated before.
+	if stale {
+		a.retries.cancel(ifi.Index)
+		delete(a.arpBindErrors, ifi.Index)
+		delete(a.ndpBindErrors, ifi.Index)
+	}
+}
+
+// removeResponders tears down any ARP/NDP responders bound to index, and
+// cancels any retry pending for it so a flapping interface doesn't
+// accumulate work. Callers must hold a.Lock().
+func (a *Announce) removeResponders(index int) {
+	a.retries.cancel(index)
+	delete(a.arpBindErrors, index)
+	delete(a.ndpBindErrors, index)
+	if client, ok := a.arps[index]; ok {
+		client.Close()
+		delete(a.arps, index)
+		a.logger.Log("interface", client.Interface().Name, "event", "deleteARPResponder", "msg", "deleted ARP responder for interface")
+	}
+	if client, ok := a.ndps[index]; ok {
+		client.Close()
+		delete(a.ndps, index)
+		a.logger.Log("interface", client.Interface().Name, "event", "deleteNDPResponder", "msg", "deleted NDP responder for interface")
+	}
 }
 
 func (a *Announce) gratuitous(ip net.IP) error {
@@ -0,0 +1,346 @@
+package layer2
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// fakeResponder is a no-op arpResponder/ndpResponder used to exercise
+// Announce's reconciliation logic without opening a real ARP/NDP socket.
+type fakeResponder struct {
+	mu         sync.Mutex
+	ifi        *net.Interface
+	closed     bool
+	gratuitous []net.IP
+	groups     map[string]net.IP
+}
+
+func newFakeResponder(ifi *net.Interface) *fakeResponder {
+	return &fakeResponder{ifi: ifi, groups: map[string]net.IP{}}
+}
+
+func (f *fakeResponder) Interface() *net.Interface { return f.ifi }
+
+func (f *fakeResponder) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeResponder) Gratuitous(ip net.IP) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gratuitous = append(f.gratuitous, ip)
+	return nil
+}
+
+func (f *fakeResponder) Watch(ip net.IP) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.groups[ip.String()] = ip
+	return nil
+}
+
+func (f *fakeResponder) Unwatch(ip net.IP) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.groups, ip.String())
+	return nil
+}
+
+func (f *fakeResponder) Groups() []net.IP {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]net.IP, 0, len(f.groups))
+	for _, ip := range f.groups {
+		out = append(out, ip)
+	}
+	return out
+}
+
+func (f *fakeResponder) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+// fakeLinkDetector is a linkDetector whose events are driven directly by a
+// test, instead of netlink or polling.
+type fakeLinkDetector struct {
+	events    chan linkEvent
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newFakeLinkDetector() *fakeLinkDetector {
+	return &fakeLinkDetector{
+		events: make(chan linkEvent, 8),
+		closed: make(chan struct{}),
+	}
+}
+
+func (d *fakeLinkDetector) Events() <-chan linkEvent { return d.events }
+
+func (d *fakeLinkDetector) Close() error {
+	d.closeOnce.Do(func() {
+		close(d.closed)
+		close(d.events)
+	})
+	return nil
+}
+
+func (d *fakeLinkDetector) isClosed() bool {
+	select {
+	case <-d.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// fakeHost overrides the interfaces/interfaceByIndex/interfaceAddrs seams in
+// host.go with an in-memory set of interfaces a test can mutate, so
+// reconcileInterface/updateInterface can be driven without touching the
+// machine's real network interfaces.
+type fakeHost struct {
+	mu    sync.Mutex
+	ifs   map[int]*net.Interface
+	addrs map[int][]net.Addr
+}
+
+func newFakeHost(t *testing.T) *fakeHost {
+	t.Helper()
+	h := &fakeHost{ifs: map[int]*net.Interface{}, addrs: map[int][]net.Addr{}}
+
+	origInterfaces, origByIndex, origAddrs := interfaces, interfaceByIndex, interfaceAddrs
+	t.Cleanup(func() {
+		interfaces = origInterfaces
+		interfaceByIndex = origByIndex
+		interfaceAddrs = origAddrs
+	})
+
+	interfaces = func() ([]net.Interface, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		out := make([]net.Interface, 0, len(h.ifs))
+		for _, ifi := range h.ifs {
+			out = append(out, *ifi)
+		}
+		return out, nil
+	}
+	interfaceByIndex = func(index int) (*net.Interface, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		ifi, ok := h.ifs[index]
+		if !ok {
+			return nil, fmt.Errorf("no interface with index %d", index)
+		}
+		cp := *ifi
+		return &cp, nil
+	}
+	interfaceAddrs = func(ifi *net.Interface) ([]net.Addr, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.addrs[ifi.Index], nil
+	}
+
+	return h
+}
+
+func (h *fakeHost) set(ifi *net.Interface, addrs []net.Addr) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cp := *ifi
+	h.ifs[ifi.Index] = &cp
+	h.addrs[ifi.Index] = addrs
+}
+
+func (h *fakeHost) remove(index int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.ifs, index)
+	delete(h.addrs, index)
+}
+
+// newTestAnnounce builds an Announce directly, bypassing New's real netlink
+// detector and socket-backed responder factory, so tests can inject fakes
+// for both.
+func newTestAnnounce(detector linkDetector, newARP func(log.Logger, *net.Interface, func(net.IP) dropReason) (arpResponder, error), newNDP func(log.Logger, *net.Interface, func(net.IP) dropReason) (ndpResponder, error)) *Announce {
+	a := &Announce{
+		logger:        log.NewNopLogger(),
+		arps:          map[int]arpResponder{},
+		ndps:          map[int]ndpResponder{},
+		ips:           map[string]net.IP{},
+		arpBindErrors: map[int]error{},
+		ndpBindErrors: map[int]error{},
+	}
+	a.retries = newRetryQueue(a.logger, a.updateInterface)
+	a.responders = &responderFactory{
+		logger:          a.logger,
+		shouldAnnounce:  a.shouldAnnounce,
+		newARPResponder: newARP,
+		newNDPResponder: newNDP,
+	}
+	a.linkDetector = detector
+	return a
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if cond() {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within 5s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func linkLocalAddr(ip string) net.Addr {
+	return &net.IPNet{IP: net.ParseIP(ip), Mask: net.CIDRMask(64, 128)}
+}
+
+// TestInterfaceScanReactsToLinkEvents drives interfaceScan with synthetic
+// link events and asserts that responders are created and torn down in
+// response, without touching the host's real interfaces.
+func TestInterfaceScanReactsToLinkEvents(t *testing.T) {
+	host := newFakeHost(t)
+	detector := newFakeLinkDetector()
+
+	var mu sync.Mutex
+	arps := map[int]*fakeResponder{}
+	ndps := map[int]*fakeResponder{}
+
+	a := newTestAnnounce(detector,
+		func(l log.Logger, ifi *net.Interface, leader func(net.IP) dropReason) (arpResponder, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			r := newFakeResponder(ifi)
+			arps[ifi.Index] = r
+			return r, nil
+		},
+		func(l log.Logger, ifi *net.Interface, leader func(net.IP) dropReason) (ndpResponder, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			r := newFakeResponder(ifi)
+			ndps[ifi.Index] = r
+			return r, nil
+		},
+	)
+	go a.interfaceScan()
+	defer a.Close()
+
+	const index = 9
+	ifi := &net.Interface{Index: index, Name: "eth9", Flags: net.FlagUp | net.FlagBroadcast, HardwareAddr: net.HardwareAddr{0, 1, 2, 3, 4, 5}}
+	host.set(ifi, nil)
+
+	detector.events <- linkEvent{Type: linkEventLinkUp, Index: index}
+	waitFor(t, func() bool {
+		a.RLock()
+		defer a.RUnlock()
+		return a.arps[index] != nil
+	})
+
+	host.set(ifi, []net.Addr{linkLocalAddr("fe80::1")})
+	detector.events <- linkEvent{Type: linkEventAddrAdd, Index: index}
+	waitFor(t, func() bool {
+		a.RLock()
+		defer a.RUnlock()
+		return a.ndps[index] != nil
+	})
+
+	host.set(ifi, nil)
+	detector.events <- linkEvent{Type: linkEventAddrDel, Index: index}
+	waitFor(t, func() bool {
+		a.RLock()
+		defer a.RUnlock()
+		return a.ndps[index] == nil
+	})
+
+	host.set(&net.Interface{Index: index, Name: "eth9", HardwareAddr: ifi.HardwareAddr}, nil)
+	detector.events <- linkEvent{Type: linkEventLinkDown, Index: index}
+	waitFor(t, func() bool {
+		a.RLock()
+		defer a.RUnlock()
+		return a.arps[index] == nil && a.ndps[index] == nil
+	})
+
+	mu.Lock()
+	arpClosed := arps[index].isClosed()
+	ndpClosed := ndps[index].isClosed()
+	mu.Unlock()
+	if !arpClosed {
+		t.Errorf("ARP responder was not closed after link went down")
+	}
+	if !ndpClosed {
+		t.Errorf("NDP responder was not closed after link went down")
+	}
+
+	host.remove(index)
+	detector.events <- linkEvent{Type: linkEventRescan}
+	waitFor(t, func() bool {
+		a.RLock()
+		defer a.RUnlock()
+		return len(a.arps) == 0 && len(a.ndps) == 0
+	})
+}
+
+// TestAnnounceCloseTearsDownRetriesAndDetector asserts that Close cancels
+// any pending retry, closes the linkDetector, and tears down every
+// responder still live.
+func TestAnnounceCloseTearsDownRetriesAndDetector(t *testing.T) {
+	host := newFakeHost(t)
+	detector := newFakeLinkDetector()
+
+	arpErr := fmt.Errorf("bind: address already in use")
+	var ndpResp *fakeResponder
+
+	a := newTestAnnounce(detector,
+		func(l log.Logger, ifi *net.Interface, leader func(net.IP) dropReason) (arpResponder, error) {
+			return nil, arpErr
+		},
+		func(l log.Logger, ifi *net.Interface, leader func(net.IP) dropReason) (ndpResponder, error) {
+			r := newFakeResponder(ifi)
+			ndpResp = r
+			return r, nil
+		},
+	)
+
+	const index = 4
+	ifi := &net.Interface{Index: index, Name: "eth4", Flags: net.FlagUp | net.FlagBroadcast, HardwareAddr: net.HardwareAddr{0, 1, 2, 3, 4, 6}}
+	host.set(ifi, []net.Addr{linkLocalAddr("fe80::2")})
+
+	a.reconcileInterface(ifi)
+
+	if _, pending := a.retries.backoffFor(index); !pending {
+		t.Fatalf("expected a retry to be pending after the ARP responder failed to bind")
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close() = %v, want nil", err)
+	}
+
+	if !detector.isClosed() {
+		t.Errorf("Close() did not close the linkDetector")
+	}
+	if _, pending := a.retries.backoffFor(index); pending {
+		t.Errorf("Close() did not cancel the pending retry")
+	}
+	if ndpResp == nil || !ndpResp.isClosed() {
+		t.Errorf("Close() did not close the NDP responder")
+	}
+	a.RLock()
+	defer a.RUnlock()
+	if len(a.arps) != 0 || len(a.ndps) != 0 {
+		t.Errorf("Close() left responders behind: arps=%v ndps=%v", a.arps, a.ndps)
+	}
+}
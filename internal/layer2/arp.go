@@ -0,0 +1,103 @@
+package layer2
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/mdlayher/arp"
+)
+
+// arpConn answers ARP requests for announced IPs on a single interface. It
+// implements the arpResponder interface.
+type arpConn struct {
+	logger log.Logger
+	ifi    *net.Interface
+	client *arp.Client
+	leader func(net.IP) dropReason
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// newARPConn opens an ARP socket on ifi and starts answering requests for
+// IPs that leader approves of.
+func newARPConn(l log.Logger, ifi *net.Interface, leader func(net.IP) dropReason) (arpResponder, error) {
+	client, err := arp.Dial(ifi)
+	if err != nil {
+		return nil, fmt.Errorf("creating ARP responder for %q: %w", ifi.Name, err)
+	}
+
+	ret := &arpConn{
+		logger: l,
+		ifi:    ifi,
+		client: client,
+		leader: leader,
+		closed: make(chan struct{}),
+	}
+	go ret.run()
+
+	return ret, nil
+}
+
+// run answers incoming ARP requests until the connection is closed.
+func (a *arpConn) run() {
+	for {
+		pkt, _, err := a.client.Read()
+		if err != nil {
+			select {
+			case <-a.closed:
+				return
+			default:
+			}
+			a.logger.Log("op", "readARP", "interface", a.ifi.Name, "error", err, "msg", "error reading ARP packet, responder shutting down")
+			return
+		}
+
+		if pkt.Operation != arp.OperationRequest {
+			continue
+		}
+
+		target := net.IP(pkt.TargetIP.AsSlice())
+		if reason := a.leader(target); reason != dropReasonNone {
+			continue
+		}
+
+		if err := a.client.Reply(pkt, a.ifi.HardwareAddr, pkt.TargetIP); err != nil {
+			a.logger.Log("op", "replyARP", "interface", a.ifi.Name, "ip", target, "error", err, "msg", "failed to send ARP reply")
+		}
+	}
+}
+
+// Interface returns the interface this responder is bound to.
+func (a *arpConn) Interface() *net.Interface {
+	return a.ifi
+}
+
+// Close stops answering requests and releases the underlying ARP socket.
+func (a *arpConn) Close() error {
+	a.closeOnce.Do(func() { close(a.closed) })
+	return a.client.Close()
+}
+
+// Gratuitous sends an unsolicited ARP announcement for ip.
+func (a *arpConn) Gratuitous(ip net.IP) error {
+	addr, ok := netip.AddrFromSlice(ip.To4())
+	if !ok {
+		return fmt.Errorf("gratuitous ARP for %q: not a valid IPv4 address", ip)
+	}
+
+	pkt, err := arp.NewPacket(arp.OperationRequest, a.ifi.HardwareAddr, addr, ethernetBroadcast, addr)
+	if err != nil {
+		return fmt.Errorf("building gratuitous ARP packet for %q: %w", ip, err)
+	}
+	if err := a.client.WriteTo(pkt, ethernetBroadcast); err != nil {
+		return fmt.Errorf("sending gratuitous ARP for %q: %w", ip, err)
+	}
+	return nil
+}
+
+// ethernetBroadcast is the all-ones Ethernet broadcast address.
+var ethernetBroadcast = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
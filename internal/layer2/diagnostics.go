@@ -0,0 +1,153 @@
+package layer2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+)
+
+// interfaceStatus is the diagnostic view of a single interface's ARP/NDP
+// responder state, served by ServeDiagnostics.
+type interfaceStatus struct {
+	Index         int      `json:"index"`
+	Name          string   `json:"name,omitempty"`
+	HardwareAddr  string   `json:"hardwareAddr,omitempty"`
+	Flags         string   `json:"flags,omitempty"`
+	ARPActive     bool     `json:"arpActive"`
+	NDPActive     bool     `json:"ndpActive"`
+	LastBindError string   `json:"lastBindError,omitempty"`
+	RetryPending  bool     `json:"retryPending"`
+	RetryBackoff  string   `json:"retryBackoff,omitempty"`
+	NDPGroups     []string `json:"ndpGroups,omitempty"`
+}
+
+// diagnosticsStatus is the JSON payload served at <prefix>/status.
+type diagnosticsStatus struct {
+	Leader     bool              `json:"leader"`
+	IPs        map[string]string `json:"ips"`
+	Interfaces []interfaceStatus `json:"interfaces"`
+}
+
+// ServeDiagnostics registers read-only JSON diagnostic endpoints under
+// prefix on mux, plus a POST <prefix>/gratuitous?ip=... endpoint that lets an
+// operator force a re-announcement without restarting the speaker.
+//
+// It's meant to be mounted on the speaker's existing metrics/health server
+// (mux.HandleFunc("/metrics", ...) today); that wiring isn't part of this
+// change because this tree doesn't contain a cmd/speaker (or any other
+// main package) to wire it into.
+func (a *Announce) ServeDiagnostics(mux *http.ServeMux, prefix string) {
+	mux.HandleFunc(prefix+"/status", a.serveDiagnosticsStatus)
+	mux.HandleFunc(prefix+"/gratuitous", a.serveGratuitous)
+}
+
+func (a *Announce) serveDiagnosticsStatus(w http.ResponseWriter, r *http.Request) {
+	a.RLock()
+	status := diagnosticsStatus{
+		Leader: a.leader,
+		IPs:    make(map[string]string, len(a.ips)),
+	}
+	for name, ip := range a.ips {
+		status.IPs[name] = ip.String()
+	}
+	status.Interfaces = a.interfaceStatusesLocked()
+	a.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		a.logger.Log("op", "serveDiagnosticsStatus", "error", err, "msg", "failed to encode diagnostics response")
+	}
+}
+
+// interfaceStatusesLocked builds the per-interface status list. Callers
+// must hold a.RLock() (or a.Lock()).
+func (a *Announce) interfaceStatusesLocked() []interfaceStatus {
+	indexes := map[int]bool{}
+	for i := range a.arps {
+		indexes[i] = true
+	}
+	for i := range a.ndps {
+		indexes[i] = true
+	}
+	for i := range a.arpBindErrors {
+		indexes[i] = true
+	}
+	for i := range a.ndpBindErrors {
+		indexes[i] = true
+	}
+
+	out := make([]interfaceStatus, 0, len(indexes))
+	for index := range indexes {
+		st := interfaceStatus{Index: index}
+
+		if client, ok := a.arps[index]; ok {
+			st.ARPActive = true
+			fillInterfaceIdentity(&st, client.Interface())
+		}
+		if client, ok := a.ndps[index]; ok {
+			st.NDPActive = true
+			fillInterfaceIdentity(&st, client.Interface())
+			for _, ip := range client.Groups() {
+				st.NDPGroups = append(st.NDPGroups, ip.String())
+			}
+		}
+		if st.Name == "" {
+			if ifi, err := interfaceByIndex(index); err == nil {
+				fillInterfaceIdentity(&st, ifi)
+			}
+		}
+		st.LastBindError = combineBindErrors(a.arpBindErrors[index], a.ndpBindErrors[index])
+		if d, pending := a.retries.backoffFor(index); pending {
+			st.RetryPending = true
+			st.RetryBackoff = d.String()
+		}
+
+		out = append(out, st)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	return out
+}
+
+func fillInterfaceIdentity(st *interfaceStatus, ifi *net.Interface) {
+	st.Name = ifi.Name
+	st.HardwareAddr = ifi.HardwareAddr.String()
+	st.Flags = ifi.Flags.String()
+}
+
+// combineBindErrors renders the last ARP and/or NDP bind error for an
+// interface into the single string interfaceStatus.LastBindError exposes.
+func combineBindErrors(arpErr, ndpErr error) string {
+	switch {
+	case arpErr != nil && ndpErr != nil:
+		return fmt.Sprintf("arp: %s; ndp: %s", arpErr, ndpErr)
+	case arpErr != nil:
+		return fmt.Sprintf("arp: %s", arpErr)
+	case ndpErr != nil:
+		return fmt.Sprintf("ndp: %s", ndpErr)
+	default:
+		return ""
+	}
+}
+
+func (a *Announce) serveGratuitous(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ip := net.ParseIP(r.URL.Query().Get("ip"))
+	if ip == nil {
+		http.Error(w, "missing or invalid ip query parameter", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.gratuitous(ip); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,12 @@
+package layer2
+
+import "net"
+
+// The following are indirections over the net package's host interface
+// lookups, so tests can drive reconcileInterface/updateInterface against
+// synthetic interfaces instead of whatever's actually on the test machine.
+var (
+	interfaces       = net.Interfaces
+	interfaceByIndex = net.InterfaceByIndex
+	interfaceAddrs   = func(ifi *net.Interface) ([]net.Addr, error) { return ifi.Addrs() }
+)
@@ -0,0 +1,34 @@
+package layer2
+
+// linkEventType identifies the kind of change a linkDetector observed.
+type linkEventType int
+
+// The set of link events a linkDetector can emit.
+const (
+	// linkEventRescan asks the receiver to reconcile every interface from
+	// scratch, e.g. because the detector has no way to identify which
+	// interface changed.
+	linkEventRescan linkEventType = iota
+	linkEventLinkUp
+	linkEventLinkDown
+	linkEventAddrAdd
+	linkEventAddrDel
+)
+
+// linkEvent describes a single change observed by a linkDetector. Index is
+// only meaningful when Type is not linkEventRescan.
+type linkEvent struct {
+	Type  linkEventType
+	Index int
+}
+
+// linkDetector watches the host for interface and address changes and
+// reports them on a channel, so that Announce doesn't need to poll.
+type linkDetector interface {
+	// Events returns the channel on which link events are delivered. It is
+	// closed once Close has been called.
+	Events() <-chan linkEvent
+
+	// Close stops the detector and releases any underlying resources.
+	Close() error
+}
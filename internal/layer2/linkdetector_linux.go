@@ -0,0 +1,88 @@
+//go:build linux
+// +build linux
+
+package layer2
+
+import (
+	"net"
+
+	"github.com/go-kit/kit/log"
+	"github.com/vishvananda/netlink"
+)
+
+// netlinkDetector is a linkDetector backed by the kernel's rtnetlink
+// notifications, so link and address changes are observed as they happen
+// instead of on a fixed polling interval.
+type netlinkDetector struct {
+	logger log.Logger
+	events chan linkEvent
+	done   chan struct{}
+}
+
+// newLinkDetector returns a linkDetector that subscribes to rtnetlink link
+// and address events.
+func newLinkDetector(l log.Logger) (linkDetector, error) {
+	d := &netlinkDetector{
+		logger: l,
+		events: make(chan linkEvent, 64),
+		done:   make(chan struct{}),
+	}
+
+	linkUpdates := make(chan netlink.LinkUpdate)
+	if err := netlink.LinkSubscribe(linkUpdates, d.done); err != nil {
+		return nil, err
+	}
+	addrUpdates := make(chan netlink.AddrUpdate)
+	if err := netlink.AddrSubscribe(addrUpdates, d.done); err != nil {
+		close(d.done)
+		return nil, err
+	}
+
+	go d.run(linkUpdates, addrUpdates)
+
+	return d, nil
+}
+
+func (d *netlinkDetector) run(linkUpdates chan netlink.LinkUpdate, addrUpdates chan netlink.AddrUpdate) {
+	defer close(d.events)
+	for {
+		select {
+		case u, ok := <-linkUpdates:
+			if !ok {
+				return
+			}
+			typ := linkEventLinkDown
+			if u.Link.Attrs().Flags&net.FlagUp != 0 {
+				typ = linkEventLinkUp
+			}
+			d.send(linkEvent{Type: typ, Index: u.Link.Attrs().Index})
+		case u, ok := <-addrUpdates:
+			if !ok {
+				return
+			}
+			typ := linkEventAddrDel
+			if u.NewAddr {
+				typ = linkEventAddrAdd
+			}
+			d.send(linkEvent{Type: typ, Index: u.LinkIndex})
+		case <-d.done:
+			return
+		}
+	}
+}
+
+func (d *netlinkDetector) send(evt linkEvent) {
+	select {
+	case d.events <- evt:
+	case <-d.done:
+	}
+}
+
+func (d *netlinkDetector) Events() <-chan linkEvent {
+	return d.events
+}
+
+func (d *netlinkDetector) Close() error {
+	close(d.done)
+	return nil
+}
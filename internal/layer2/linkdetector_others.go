@@ -0,0 +1,57 @@
+//go:build !linux
+// +build !linux
+
+package layer2
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// pollingDetector is the linkDetector used on platforms without rtnetlink
+// (Windows, BSD). It has no way to identify which interface changed, so it
+// just asks for a full rescan on a timer, matching the old polling
+// behaviour.
+type pollingDetector struct {
+	events chan linkEvent
+	done   chan struct{}
+}
+
+// newLinkDetector returns a linkDetector that periodically asks for a full
+// rescan of all interfaces.
+func newLinkDetector(l log.Logger) (linkDetector, error) {
+	d := &pollingDetector{
+		events: make(chan linkEvent, 1),
+		done:   make(chan struct{}),
+	}
+	go d.run()
+	return d, nil
+}
+
+func (d *pollingDetector) run() {
+	defer close(d.events)
+	t := time.NewTicker(10 * time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			select {
+			case d.events <- linkEvent{Type: linkEventRescan}:
+			case <-d.done:
+				return
+			}
+		case <-d.done:
+			return
+		}
+	}
+}
+
+func (d *pollingDetector) Events() <-chan linkEvent {
+	return d.events
+}
+
+func (d *pollingDetector) Close() error {
+	close(d.done)
+	return nil
+}
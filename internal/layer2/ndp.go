@@ -0,0 +1,235 @@
+package layer2
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	ndpNeighborSolicitation   = 135
+	ndpNeighborAdvertisement  = 136
+	ndpOptSourceLinkLayerAddr = 1
+	ndpOptTargetLinkLayerAddr = 2
+	ndpFlagRouter             = 1 << 31
+	ndpFlagSolicited          = 1 << 30
+	ndpFlagOverride           = 1 << 29
+)
+
+// ndpConn answers NDP neighbor solicitations for announced IPs on a single
+// interface, and tracks which solicited-node multicast groups it's joined
+// on behalf of watched IPs. It implements the ndpResponder interface.
+type ndpConn struct {
+	logger log.Logger
+	ifi    *net.Interface
+	conn   *icmp.PacketConn
+	pc     *ipv6.PacketConn
+	leader func(net.IP) dropReason
+
+	mu     sync.Mutex
+	groups map[string]net.IP // watched IP (keyed by String()) -> IP
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// newNDPConn opens an ICMPv6 socket on ifi and starts answering neighbor
+// solicitations for IPs that leader approves of.
+func newNDPConn(l log.Logger, ifi *net.Interface, leader func(net.IP) dropReason) (ndpResponder, error) {
+	conn, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return nil, fmt.Errorf("creating NDP responder for %q: %w", ifi.Name, err)
+	}
+
+	pc := conn.IPv6PacketConn()
+	if err := pc.SetControlMessage(ipv6.FlagDst|ipv6.FlagInterface, true); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("enabling control messages for %q: %w", ifi.Name, err)
+	}
+
+	ret := &ndpConn{
+		logger: l,
+		ifi:    ifi,
+		conn:   conn,
+		pc:     pc,
+		leader: leader,
+		groups: map[string]net.IP{},
+		closed: make(chan struct{}),
+	}
+	go ret.run()
+
+	return ret, nil
+}
+
+// run answers incoming neighbor solicitations until the connection is
+// closed.
+func (n *ndpConn) run() {
+	buf := make([]byte, 1500)
+	for {
+		nBytes, cm, _, err := n.pc.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-n.closed:
+				return
+			default:
+			}
+			n.logger.Log("op", "readNDP", "interface", n.ifi.Name, "error", err, "msg", "error reading NDP packet, responder shutting down")
+			return
+		}
+
+		// The socket receives ICMPv6 from every interface; only answer
+		// solicitations that arrived on ours.
+		if cm == nil || cm.IfIndex != n.ifi.Index {
+			continue
+		}
+
+		target, ok := parseNeighborSolicitation(buf[:nBytes])
+		if !ok {
+			continue
+		}
+
+		if reason := n.leader(target); reason != dropReasonNone {
+			continue
+		}
+
+		if err := n.advertise(target); err != nil {
+			n.logger.Log("op", "replyNDP", "interface", n.ifi.Name, "ip", target, "error", err, "msg", "failed to send neighbor advertisement")
+		}
+	}
+}
+
+// advertise sends an unsolicited neighbor advertisement for ip, claiming
+// this interface's hardware address.
+//
+// The destination address is scoped with the interface's numeric index
+// rather than its name: Go's net package caches name->zone-index lookups
+// (ipv6ZoneCache in net/interface.go) and only force-refreshes when the
+// name is absent from the cache, so a name zone can still resolve to a
+// since-deleted interface if another one was recreated under the same name
+// within the cache's refresh window. The numeric form bypasses that cache
+// entirely.
+func (n *ndpConn) advertise(ip net.IP) error {
+	msg := neighborAdvertisement(ip, n.ifi.HardwareAddr, true)
+	_, err := n.pc.WriteTo(msg, nil, &net.IPAddr{IP: net.IPv6linklocalallnodes, Zone: strconv.Itoa(n.ifi.Index)})
+	return err
+}
+
+// Interface returns the interface this responder is bound to.
+func (n *ndpConn) Interface() *net.Interface {
+	return n.ifi
+}
+
+// Close stops answering requests and releases the underlying ICMPv6 socket.
+func (n *ndpConn) Close() error {
+	n.closeOnce.Do(func() { close(n.closed) })
+	return n.conn.Close()
+}
+
+// Gratuitous sends an unsolicited neighbor advertisement for ip.
+func (n *ndpConn) Gratuitous(ip net.IP) error {
+	if err := n.advertise(ip); err != nil {
+		return fmt.Errorf("gratuitous NDP advertisement for %q: %w", ip, err)
+	}
+	return nil
+}
+
+// Watch joins the solicited-node multicast group for ip, so the kernel
+// delivers neighbor solicitations for it to this socket.
+func (n *ndpConn) Watch(ip net.IP) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	key := ip.String()
+	if _, ok := n.groups[key]; ok {
+		return nil
+	}
+
+	group := solicitedNodeMulticast(ip)
+	if err := n.pc.JoinGroup(n.ifi, &net.IPAddr{IP: group}); err != nil {
+		return fmt.Errorf("joining solicited-node multicast group for %q: %w", ip, err)
+	}
+
+	n.groups[key] = ip
+	return nil
+}
+
+// Unwatch leaves the solicited-node multicast group for ip.
+func (n *ndpConn) Unwatch(ip net.IP) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	key := ip.String()
+	if _, ok := n.groups[key]; !ok {
+		return nil
+	}
+
+	group := solicitedNodeMulticast(ip)
+	if err := n.pc.LeaveGroup(n.ifi, &net.IPAddr{IP: group}); err != nil {
+		return fmt.Errorf("leaving solicited-node multicast group for %q: %w", ip, err)
+	}
+
+	delete(n.groups, key)
+	return nil
+}
+
+// Groups returns the IPs whose solicited-node multicast group is currently
+// being watched.
+func (n *ndpConn) Groups() []net.IP {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	out := make([]net.IP, 0, len(n.groups))
+	for _, ip := range n.groups {
+		out = append(out, ip)
+	}
+	return out
+}
+
+// solicitedNodeMulticast returns the solicited-node multicast address for
+// ip, ff02::1:ffXX:XXXX built from ip's low 24 bits.
+func solicitedNodeMulticast(ip net.IP) net.IP {
+	ip16 := ip.To16()
+	group := net.ParseIP("ff02::1:ff00:0")
+	copy(group[13:], ip16[13:])
+	return group
+}
+
+// parseNeighborSolicitation extracts the target address from a minimal,
+// option-free ICMPv6 neighbor solicitation. It reports false if buf isn't
+// one.
+func parseNeighborSolicitation(buf []byte) (net.IP, bool) {
+	// type(1) code(1) checksum(2) reserved(4) target(16)
+	if len(buf) < 24 || buf[0] != ndpNeighborSolicitation {
+		return nil, false
+	}
+	target := make(net.IP, 16)
+	copy(target, buf[8:24])
+	return target, true
+}
+
+// neighborAdvertisement builds an unsolicited, overriding ICMPv6 neighbor
+// advertisement for ip claiming hwAddr. The checksum is left zero: the
+// kernel fills it in for ICMPv6 raw sockets opened via icmp.ListenPacket.
+func neighborAdvertisement(ip net.IP, hwAddr net.HardwareAddr, override bool) []byte {
+	var flags uint32
+	if override {
+		flags |= ndpFlagOverride
+	}
+
+	const headerLen = 8 // type(1) code(1) checksum(2) flags(4)
+	buf := make([]byte, headerLen+16+2+len(hwAddr))
+	buf[0] = ndpNeighborAdvertisement
+	binary.BigEndian.PutUint32(buf[4:8], flags)
+	copy(buf[headerLen:headerLen+16], ip.To16())
+	buf[headerLen+16] = ndpOptTargetLinkLayerAddr
+	buf[headerLen+17] = uint8((2 + len(hwAddr)) / 8)
+	copy(buf[headerLen+18:], hwAddr)
+
+	return buf
+}
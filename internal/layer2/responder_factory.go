@@ -0,0 +1,89 @@
+package layer2
+
+import (
+	"net"
+
+	"github.com/go-kit/kit/log"
+)
+
+// responder is the behavior common to ARP and NDP responders.
+type responder interface {
+	// Interface returns the interface this responder is bound to.
+	Interface() *net.Interface
+	// Close stops the responder and releases its socket.
+	Close() error
+	// Gratuitous sends an unsolicited announcement for ip.
+	Gratuitous(ip net.IP) error
+}
+
+// arpResponder answers ARP requests on a single interface.
+type arpResponder = responder
+
+// ndpResponder answers NDP neighbor solicitations on a single interface,
+// and tracks which solicited-node multicast groups it's watching on behalf
+// of announced IPs.
+type ndpResponder interface {
+	responder
+	// Watch joins the solicited-node multicast group for ip.
+	Watch(ip net.IP) error
+	// Unwatch leaves the solicited-node multicast group for ip.
+	Unwatch(ip net.IP) error
+	// Groups returns the IPs whose solicited-node multicast group is
+	// currently being watched.
+	Groups() []net.IP
+}
+
+// responderFactory builds ARP/NDP responders for an interface index. It
+// always resolves the interface fresh via interfaceByIndex right before
+// construction, rather than reusing whatever *net.Interface the caller
+// happened to have lying around, so a responder never ends up bound against
+// an interface that has since been deleted and recreated under the same
+// index.
+type responderFactory struct {
+	logger         log.Logger
+	shouldAnnounce func(net.IP) dropReason
+
+	// newARPResponder and newNDPResponder build the real responders. They're
+	// overridable fields, rather than direct calls to newARPConn/newNDPConn,
+	// so tests can inject fakes without opening a real ARP/NDP socket.
+	newARPResponder func(log.Logger, *net.Interface, func(net.IP) dropReason) (arpResponder, error)
+	newNDPResponder func(log.Logger, *net.Interface, func(net.IP) dropReason) (ndpResponder, error)
+}
+
+// newResponderFactory returns a responderFactory that builds real,
+// socket-backed responders.
+func newResponderFactory(l log.Logger, shouldAnnounce func(net.IP) dropReason) *responderFactory {
+	return &responderFactory{
+		logger:          l,
+		shouldAnnounce:  shouldAnnounce,
+		newARPResponder: newARPConn,
+		newNDPResponder: newNDPConn,
+	}
+}
+
+// newARP resolves index and constructs an ARP responder for it.
+func (f *responderFactory) newARP(index int) (arpResponder, error) {
+	ifi, err := interfaceByIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	return f.newARPResponder(f.logger, ifi, f.shouldAnnounce)
+}
+
+// newNDP resolves index and constructs an NDP responder for it.
+func (f *responderFactory) newNDP(index int) (ndpResponder, error) {
+	ifi, err := interfaceByIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	return f.newNDPResponder(f.logger, ifi, f.shouldAnnounce)
+}
+
+// sameInterface reports whether a and b are the same logical interface,
+// rather than merely sharing an ifindex. The kernel reuses ifindexes when an
+// interface is deleted and recreated (common with veth churn or secondary
+// NIC hot-plug), and Go's net package caches name->zone-index mappings, so
+// an index match alone isn't enough to trust a cached responder.
+func sameInterface(a, b *net.Interface) bool {
+	return a.Name == b.Name && a.HardwareAddr.String() == b.HardwareAddr.String()
+}
@@ -0,0 +1,100 @@
+package layer2
+
+import (
+	"net"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// TestSameInterface exercises the identity check used to decide whether a
+// cached responder still belongs to the interface at its ifindex, e.g.
+// after veth churn recycles an index under a stable name but a new MAC.
+func TestSameInterface(t *testing.T) {
+	base := &net.Interface{Index: 4, Name: "eth0", HardwareAddr: net.HardwareAddr{0, 1, 2, 3, 4, 5}}
+
+	tests := []struct {
+		name string
+		ifi  *net.Interface
+		want bool
+	}{
+		{
+			name: "identical",
+			ifi:  &net.Interface{Index: 4, Name: "eth0", HardwareAddr: net.HardwareAddr{0, 1, 2, 3, 4, 5}},
+			want: true,
+		},
+		{
+			name: "recreated with new MAC, same name and index",
+			ifi:  &net.Interface{Index: 4, Name: "eth0", HardwareAddr: net.HardwareAddr{0, 1, 2, 3, 4, 6}},
+			want: false,
+		},
+		{
+			name: "renamed, same MAC and index",
+			ifi:  &net.Interface{Index: 4, Name: "eth1", HardwareAddr: net.HardwareAddr{0, 1, 2, 3, 4, 5}},
+			want: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sameInterface(base, tc.ifi); got != tc.want {
+				t.Errorf("sameInterface(%v, %v) = %v, want %v", base, tc.ifi, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestReconcileInterfaceRebuildsResponderOnRecycledIndex exercises
+// reconcileInterface end-to-end with a fake responder factory, asserting
+// that when an ifindex is recycled under a stable name but a new MAC, the
+// stale responder is closed and a brand new responder object is built for
+// it, rather than the cached one being reused as-is.
+func TestReconcileInterfaceRebuildsResponderOnRecycledIndex(t *testing.T) {
+	host := newFakeHost(t)
+	detector := newFakeLinkDetector()
+
+	var built []*fakeResponder
+	a := newTestAnnounce(detector,
+		func(l log.Logger, ifi *net.Interface, leader func(net.IP) dropReason) (arpResponder, error) {
+			r := newFakeResponder(ifi)
+			built = append(built, r)
+			return r, nil
+		},
+		func(l log.Logger, ifi *net.Interface, leader func(net.IP) dropReason) (ndpResponder, error) {
+			return newFakeResponder(ifi), nil
+		},
+	)
+
+	const index = 12
+	ifi := &net.Interface{Index: index, Name: "veth0", Flags: net.FlagUp | net.FlagBroadcast, HardwareAddr: net.HardwareAddr{0, 1, 2, 3, 4, 7}}
+	host.set(ifi, nil)
+	a.reconcileInterface(ifi)
+
+	if len(built) != 1 {
+		t.Fatalf("got %d responders built, want 1", len(built))
+	}
+	first := built[0]
+
+	// Simulate the veth being torn down and a new one recreated with a new
+	// MAC, but recycled onto the same ifindex and name.
+	recycled := &net.Interface{Index: index, Name: "veth0", Flags: net.FlagUp | net.FlagBroadcast, HardwareAddr: net.HardwareAddr{0, 1, 2, 3, 4, 8}}
+	host.set(recycled, nil)
+	a.reconcileInterface(recycled)
+
+	if len(built) != 2 {
+		t.Fatalf("got %d responders built after index recycle, want 2", len(built))
+	}
+	if !first.isClosed() {
+		t.Errorf("stale responder for the recycled index was not closed")
+	}
+
+	a.RLock()
+	current := a.arps[index]
+	a.RUnlock()
+	if current == first {
+		t.Errorf("reconcileInterface kept the stale responder instead of building a new one")
+	}
+	if current != built[1] {
+		t.Errorf("a.arps[%d] is not the newly built responder", index)
+	}
+}
@@ -0,0 +1,107 @@
+package layer2
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	retryInitialBackoff = 250 * time.Millisecond
+	retryMaxBackoff     = 30 * time.Second
+)
+
+// retryQueue retries a failed operation for an interface index with
+// exponential backoff, e.g. because responder creation failed while the
+// interface's address was still tentative under Duplicate Address
+// Detection. A flapping interface is coalesced down to a single pending
+// retry rather than accumulating one per failure.
+type retryQueue struct {
+	logger log.Logger
+	retry  func(index int)
+
+	mu      sync.Mutex
+	pending map[int]*time.Timer
+	backoff map[int]time.Duration
+	closed  bool
+}
+
+// newRetryQueue returns a retryQueue that calls retry after the backoff for
+// a given index elapses.
+func newRetryQueue(l log.Logger, retry func(index int)) *retryQueue {
+	return &retryQueue{
+		logger:  l,
+		retry:   retry,
+		pending: map[int]*time.Timer{},
+		backoff: map[int]time.Duration{},
+	}
+}
+
+// scheduleRetry arranges for index to be retried after its current backoff,
+// then doubles the backoff for next time, up to retryMaxBackoff. If index
+// already has a retry pending, scheduleRetry is a no-op.
+func (q *retryQueue) scheduleRetry(index int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	if _, ok := q.pending[index]; ok {
+		return
+	}
+
+	d, ok := q.backoff[index]
+	if !ok {
+		d = retryInitialBackoff
+	}
+	q.backoff[index] = nextBackoff(d)
+
+	q.pending[index] = time.AfterFunc(d, func() {
+		q.mu.Lock()
+		delete(q.pending, index)
+		q.mu.Unlock()
+		q.retry(index)
+	})
+}
+
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > retryMaxBackoff {
+		d = retryMaxBackoff
+	}
+	return d
+}
+
+// cancel drops any pending retry and resets the backoff for index, e.g.
+// because the interface went down or was deleted.
+func (q *retryQueue) cancel(index int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.backoff, index)
+	if t, ok := q.pending[index]; ok {
+		t.Stop()
+		delete(q.pending, index)
+	}
+}
+
+// backoffFor reports whether index currently has a retry pending, and the
+// backoff the next retry of index will be scheduled with: the pending one's
+// backoff doubled, up to retryMaxBackoff. Used by diagnostics.
+func (q *retryQueue) backoffFor(index int) (d time.Duration, pending bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, pending = q.pending[index]
+	return q.backoff[index], pending
+}
+
+// close stops every pending retry. No further retries will be scheduled.
+func (q *retryQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	for index, t := range q.pending {
+		t.Stop()
+		delete(q.pending, index)
+	}
+}
@@ -0,0 +1,135 @@
+package layer2
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// TestRetryQueueEventuallySucceeds simulates an operation (e.g. binding an
+// NDP responder) that fails with something like EADDRNOTAVAIL for the first
+// few attempts, as happens while an address is still tentative under DAD,
+// and asserts the queue keeps retrying until it succeeds.
+func TestRetryQueueEventuallySucceeds(t *testing.T) {
+	const wantFailures = 3
+	var attempts int32
+	done := make(chan struct{})
+
+	var q *retryQueue
+	q = newRetryQueue(log.NewNopLogger(), func(index int) {
+		if atomic.AddInt32(&attempts, 1) <= wantFailures {
+			q.scheduleRetry(index)
+			return
+		}
+		close(done)
+	})
+	defer q.close()
+
+	q.scheduleRetry(1)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("responder was never created, saw %d attempts", atomic.LoadInt32(&attempts))
+	}
+
+	if got, want := atomic.LoadInt32(&attempts), int32(wantFailures+1); got != want {
+		t.Errorf("attempts = %d, want %d", got, want)
+	}
+}
+
+// TestRetryQueueCancelCoalesces asserts that cancelling a pending retry
+// (e.g. because the interface went down) stops it from firing, so a
+// flapping interface doesn't accumulate retries.
+func TestRetryQueueCancelCoalesces(t *testing.T) {
+	var attempts int32
+	q := newRetryQueue(log.NewNopLogger(), func(index int) {
+		atomic.AddInt32(&attempts, 1)
+	})
+	defer q.close()
+
+	q.scheduleRetry(1)
+	q.cancel(1)
+
+	time.Sleep(500 * time.Millisecond)
+	if got := atomic.LoadInt32(&attempts); got != 0 {
+		t.Errorf("attempts = %d, want 0 after cancel", got)
+	}
+}
+
+// TestRetryQueueScheduleIsIdempotent asserts that scheduling an index that
+// already has a retry pending doesn't create a second timer.
+func TestRetryQueueScheduleIsIdempotent(t *testing.T) {
+	var attempts int32
+	q := newRetryQueue(log.NewNopLogger(), func(index int) {
+		atomic.AddInt32(&attempts, 1)
+	})
+	defer q.close()
+
+	q.scheduleRetry(1)
+	q.scheduleRetry(1)
+	q.scheduleRetry(1)
+
+	time.Sleep(500 * time.Millisecond)
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
+
+// TestReconcileInterfaceRetriesUntilResponderSucceeds exercises the retry
+// queue through the real updateInterface/reconcileInterface path, with a
+// fake ARP responder that fails with something like EADDRNOTAVAIL for the
+// first couple of attempts. It asserts a responder eventually gets built
+// and that it can then send gratuitous announcements.
+func TestReconcileInterfaceRetriesUntilResponderSucceeds(t *testing.T) {
+	host := newFakeHost(t)
+	detector := newFakeLinkDetector()
+
+	const wantFailures = 2
+	var attempts int32
+	var built *fakeResponder
+
+	a := newTestAnnounce(detector,
+		func(l log.Logger, ifi *net.Interface, leader func(net.IP) dropReason) (arpResponder, error) {
+			if atomic.AddInt32(&attempts, 1) <= wantFailures {
+				return nil, fmt.Errorf("bind %q: address not available", ifi.Name)
+			}
+			built = newFakeResponder(ifi)
+			return built, nil
+		},
+		func(l log.Logger, ifi *net.Interface, leader func(net.IP) dropReason) (ndpResponder, error) {
+			return newFakeResponder(ifi), nil
+		},
+	)
+	defer a.Close()
+
+	const index = 6
+	ifi := &net.Interface{Index: index, Name: "eth6", Flags: net.FlagUp | net.FlagBroadcast, HardwareAddr: net.HardwareAddr{0, 1, 2, 3, 4, 9}}
+	host.set(ifi, nil)
+
+	a.reconcileInterface(ifi)
+
+	waitFor(t, func() bool {
+		a.RLock()
+		defer a.RUnlock()
+		return a.arps[index] != nil
+	})
+
+	if got, want := atomic.LoadInt32(&attempts), int32(wantFailures+1); got != want {
+		t.Errorf("attempts = %d, want %d", got, want)
+	}
+	if _, pending := a.retries.backoffFor(index); pending {
+		t.Errorf("a retry is still pending after the responder was successfully created")
+	}
+
+	if err := built.Gratuitous(net.ParseIP("192.0.2.1")); err != nil {
+		t.Fatalf("Gratuitous() = %v, want nil", err)
+	}
+	if got := built.gratuitous; len(got) != 1 || !got[0].Equal(net.ParseIP("192.0.2.1")) {
+		t.Errorf("gratuitous = %v, want [192.0.2.1]", got)
+	}
+}